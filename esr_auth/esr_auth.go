@@ -1,26 +1,45 @@
 package main
 
 import (
-	"encoding/json"
+	"github.com/JakubKwak/go-learn-ca/pkg/health"
+	"github.com/JakubKwak/go-learn-ca/pkg/registry"
+	"github.com/JakubKwak/go-learn-ca/pkg/store"
 	"github.com/gorilla/mux"
 	"github.com/joho/godotenv"
 	"log"
 	"net/http"
 	"os"
+	"os/signal"
+	"syscall"
+	"time"
 )
 
 // .env config struct
 type Config struct {
-	apiKey    string
-	usersPath string
-	port      string
+	apiKey       string
+	port         string
+	addr         string
+	registryUrl  string
+	registryFile string
+	storeKind    string
+	storeDsn     string
 }
 
-// User struct for loading users from json
-type User struct {
-	Id  string
-	Key string
-}
+// serviceName is how this service registers itself in the registry
+const serviceName = "esr_auth"
+
+// registrationTtl is how long a registration is valid for before it must be renewed
+const registrationTtl = 15 * time.Second
+
+// User is an alias for the auth entry shape persisted by pkg/store
+type User = store.User
+
+// userStore resolves API keys to user ids, backed by whichever
+// implementation STORE selects
+var userStore store.UserStore
+
+// healthTracker exposes /healthz, /readyz and /metrics for this service
+var healthTracker = health.New()
 
 // config struct to hold URLs and API keys
 var config Config
@@ -34,14 +53,58 @@ func init() {
 
 func main() {
 	GetEnv()
+	us, err := store.NewUserStore(config.storeKind, config.storeDsn)
+	if err != nil {
+		log.Fatal(err)
+	}
+	userStore = us
+	RegisterService()
+	healthTracker.StartKeepalive(serviceName, 30*time.Second)
 	handleRequests()
 }
 
 // Gets environment variables and store them in config struct
 func GetEnv() {
 	config.apiKey = GetEnvVar("APIKEY")
-	config.usersPath = GetEnvVar("USERSPATH")
 	config.port = GetEnvVar("PORT")
+	config.addr = GetEnvVar("ADDR")
+	config.registryUrl = GetEnvVar("REGISTRYURL")
+	config.registryFile = os.Getenv("REGISTRYFILE")
+	config.storeKind = GetEnvVar("STORE")
+	config.storeDsn = GetEnvVar("STOREDSN")
+}
+
+// buildRegistry returns an HTTPRegistry talking to esr_registry, or a
+// FallbackRegistry over it and a FileRegistry if REGISTRYFILE is set, so
+// peers stay resolvable if esr_registry itself is unreachable.
+func buildRegistry() registry.Registry {
+	primary := registry.NewHTTPRegistry(config.registryUrl)
+	if config.registryFile == "" {
+		return primary
+	}
+	secondary, err := registry.NewFileRegistry(config.registryFile)
+	if err != nil {
+		log.Print("Warning: could not load registry fallback file: " + err.Error())
+		return primary
+	}
+	return registry.NewFallbackRegistry(primary, secondary)
+}
+
+// Registers this instance with esr_registry, keeps the registration alive
+// with periodic heartbeats, and deregisters it on SIGTERM
+func RegisterService() {
+	reg := buildRegistry()
+	stop := make(chan struct{})
+	if err := registry.KeepAlive(reg, serviceName, config.addr, registrationTtl, stop); err != nil {
+		log.Fatal(err)
+	}
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, syscall.SIGTERM)
+	go func() {
+		<-sigs
+		close(stop)
+		os.Exit(0)
+	}()
 }
 
 // Gets a specific var from env, or log fatal if missing
@@ -58,45 +121,31 @@ func GetEnvVar(varName string) string {
 func handleRequests() {
 	router := mux.NewRouter().StrictSlash(true)
 	router.HandleFunc("/esr_auth/{key}", Authenticate).Methods("GET")
+	healthTracker.Mount(router)
 	log.Fatal(http.ListenAndServe(":"+config.port, router))
 }
 
 // Responds to HTTP GET requests, responding with the user ID which matches the given key
 // The HTTP request must contain the key to authenticate in the address
-// It would be more appropiate to store user data in a database rather than a JSON file, but that is outside
-// of the scope of this CA, and a JSON file is sufficient to demonstrate the functionality of this service.
 func Authenticate(w http.ResponseWriter, r *http.Request) {
+	healthTracker.Inc("requests_handled")
 	if r.Header.Get("x-api-key") != config.apiKey {
+		healthTracker.Inc("auth_failures")
 		w.WriteHeader(http.StatusBadRequest)
 		return
 	}
 	vars := mux.Vars(r)
 	key := vars["key"]
-	usersFile, err := os.Open(config.usersPath)
-	if err != nil {
-		log.Print(err)
-		w.WriteHeader(http.StatusInternalServerError)
-		w.Write(nil)
-		return
-	}
-	var users []User
-	usersDecoder := json.NewDecoder(usersFile)
-	if err = usersDecoder.Decode(&users); err != nil {
+	var id string
+	if user, err := userStore.LookupByKey(key); err == nil {
+		id = user.Id
+		log.Print("User authenticated: " + id)
+	} else if err != store.ErrNotFound {
 		log.Print(err)
 		w.WriteHeader(http.StatusInternalServerError)
 		w.Write(nil)
 		return
 	}
-	var id string
-	for _, user := range users {
-		if user.Key == key {
-			id = user.Id
-			break
-		}
-	}
-	if id != "" {
-		log.Print("User authenticated: " + id)
-	}
 	w.WriteHeader(http.StatusOK)
 	w.Write([]byte(id))
 }
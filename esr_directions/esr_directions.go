@@ -1,33 +1,50 @@
 package main
 
 import (
-	"encoding/json"
+	"github.com/JakubKwak/go-learn-ca/pkg/bind"
+	"github.com/JakubKwak/go-learn-ca/pkg/health"
+	"github.com/JakubKwak/go-learn-ca/pkg/registry"
 	"github.com/gorilla/mux"
 	"github.com/joho/godotenv"
 	"io/ioutil"
 	"log"
 	"net/http"
 	"os"
+	"os/signal"
 	"strings"
+	"syscall"
+	"time"
 )
 
 // .env config struct
 type Config struct {
-	googleKey string
-	googleUrl string
-	apiKey    string
-	port      string
+	googleKey    string
+	googleUrl    string
+	apiKey       string
+	port         string
+	addr         string
+	registryUrl  string
+	registryFile string
 }
 
+// serviceName is how this service registers itself in the registry
+const serviceName = "esr_directions"
+
+// registrationTtl is how long a registration is valid for before it must be renewed
+const registrationTtl = 15 * time.Second
+
 // Journey information struct
 type Journey struct {
-	Start string
-	End   string
+	Start string `form:"start"`
+	End   string `form:"end"`
 }
 
 // config struct to hold URLs and API keys
 var config Config
 
+// healthTracker exposes /healthz, /readyz and /metrics for this service
+var healthTracker = health.New()
+
 // invoked before main
 func init() {
 	if err := godotenv.Load(); err != nil {
@@ -37,6 +54,8 @@ func init() {
 
 func main() {
 	GetEnv()
+	RegisterService()
+	healthTracker.StartKeepalive(serviceName, 30*time.Second)
 	handleRequests()
 }
 
@@ -44,6 +63,7 @@ func main() {
 func handleRequests() {
 	router := mux.NewRouter().StrictSlash(true)
 	router.HandleFunc("/esr_directions", GetDirections).Methods("GET")
+	healthTracker.Mount(router)
 	log.Fatal(http.ListenAndServe(":"+config.port, router))
 }
 
@@ -53,6 +73,42 @@ func GetEnv() {
 	config.googleUrl = GetEnvVar("GOOGLEURL")
 	config.apiKey = GetEnvVar("APIKEY")
 	config.port = GetEnvVar("PORT")
+	config.addr = GetEnvVar("ADDR")
+	config.registryUrl = GetEnvVar("REGISTRYURL")
+	config.registryFile = os.Getenv("REGISTRYFILE")
+}
+
+// buildRegistry returns an HTTPRegistry talking to esr_registry, or a
+// FallbackRegistry over it and a FileRegistry if REGISTRYFILE is set, so
+// peers stay resolvable if esr_registry itself is unreachable.
+func buildRegistry() registry.Registry {
+	primary := registry.NewHTTPRegistry(config.registryUrl)
+	if config.registryFile == "" {
+		return primary
+	}
+	secondary, err := registry.NewFileRegistry(config.registryFile)
+	if err != nil {
+		log.Print("Warning: could not load registry fallback file: " + err.Error())
+		return primary
+	}
+	return registry.NewFallbackRegistry(primary, secondary)
+}
+
+// Registers this instance with esr_registry, keeps the registration alive
+// with periodic heartbeats, and deregisters it on SIGTERM
+func RegisterService() {
+	reg := buildRegistry()
+	stop := make(chan struct{})
+	if err := registry.KeepAlive(reg, serviceName, config.addr, registrationTtl, stop); err != nil {
+		log.Fatal(err)
+	}
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, syscall.SIGTERM)
+	go func() {
+		<-sigs
+		close(stop)
+		os.Exit(0)
+	}()
 }
 
 // Gets a specific var from env, or log fatal if missing
@@ -66,17 +122,18 @@ func GetEnvVar(varName string) string {
 }
 
 // Handles GET requests, responding with directions
-// Request must have a valid 'x-api-key' in header and a 'Journey' structured JSON in body
+// Request must have a valid 'x-api-key' in header and 'start'/'end' query parameters
 // This func uses the 'googleURL' and 'googleKey' env vars to send an HTTP GET request to Google's
 // Directions API. It then responds with the received JSON.
 func GetDirections(w http.ResponseWriter, r *http.Request) {
+	healthTracker.Inc("requests_handled")
 	if r.Header.Get("x-api-key") != config.apiKey {
+		healthTracker.Inc("auth_failures")
 		w.WriteHeader(http.StatusBadRequest)
 		return
 	}
-	decoder := json.NewDecoder(r.Body)
 	var journey Journey
-	if err := decoder.Decode(&journey); err != nil {
+	if err := bind.Bind(r, &journey); err != nil {
 		w.WriteHeader(http.StatusBadRequest)
 		return
 	}
@@ -92,9 +149,11 @@ func GetDirections(w http.ResponseWriter, r *http.Request) {
 				w.Write([]byte(body))
 				log.Print("Directions request complete: " + start + " to " + end)
 			} else {
+				healthTracker.Inc("upstream_errors")
 				w.WriteHeader(http.StatusInternalServerError)
 			}
 		} else {
+			healthTracker.Inc("upstream_errors")
 			w.WriteHeader(http.StatusInternalServerError)
 		}
 	} else {
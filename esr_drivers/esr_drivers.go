@@ -1,32 +1,83 @@
 package main
 
 import (
-	"encoding/json"
+	"encoding/xml"
+	"github.com/JakubKwak/go-learn-ca/pkg/bind"
+	"github.com/JakubKwak/go-learn-ca/pkg/health"
+	"github.com/JakubKwak/go-learn-ca/pkg/registry"
+	"github.com/JakubKwak/go-learn-ca/pkg/store"
 	"github.com/gorilla/mux"
 	"github.com/joho/godotenv"
 	"io/ioutil"
 	"log"
 	"net/http"
 	"os"
+	"os/signal"
+	"syscall"
+	"time"
 )
 
 // .env config struct
 type Config struct {
-	apiKey  string
-	authKey string
-	authUrl string
-	port    string
+	apiKey       string
+	authKey      string
+	port         string
+	addr         string
+	registryUrl  string
+	registryFile string
+	storeKind    string
+	storeDsn     string
 }
 
-// Driver struct
-type Driver struct {
-	Id   string
-	Name string
-	Rate float64
+// serviceName is how this service registers itself in the registry
+const serviceName = "esr_drivers"
+
+// registrationTtl is how long a registration is valid for before it must be renewed
+const registrationTtl = 15 * time.Second
+
+// reg is the registry client used to resolve esr_auth
+var reg registry.Registry
+
+// authBalancer spreads Authenticate calls across every live esr_auth instance
+var authBalancer registry.Balancer
+
+// Driver is an alias for the roster entry shape persisted by pkg/store
+type Driver = store.Driver
+
+// driverStore holds the current roster of drivers, backed by whichever
+// implementation STORE selects
+var driverStore store.DriverStore
+
+// DriverList is a ListDrivers() snapshot, keyed by driver name. It marshals
+// to JSON as that same map, but implements xml.Marshaler since encoding/xml
+// cannot marshal Go maps directly.
+type DriverList map[string]Driver
+
+// driverListEntry is one <driver> element of a marshaled DriverList
+type driverListEntry struct {
+	XMLName xml.Name `xml:"driver"`
+	Name    string
+	Id      string
+	Rate    float64
+}
+
+// MarshalXML encodes the roster as a <drivers><driver>...</driver></drivers> list.
+func (d DriverList) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	start.Name = xml.Name{Local: "drivers"}
+	if err := e.EncodeToken(start); err != nil {
+		return err
+	}
+	for name, driver := range d {
+		entry := driverListEntry{Name: name, Id: driver.Id, Rate: driver.Rate}
+		if err := e.Encode(entry); err != nil {
+			return err
+		}
+	}
+	return e.EncodeToken(start.End())
 }
 
-// Drivers map to hold the current roster of drivers
-var drivers map[string]Driver
+// healthTracker exposes /healthz, /readyz and /metrics for this service
+var healthTracker = health.New()
 
 // config struct to hold URLs and API keys
 var config Config
@@ -40,7 +91,14 @@ func init() {
 
 func main() {
 	GetEnv()
-	drivers = make(map[string]Driver)
+	ds, err := store.NewDriverStore(config.storeKind, config.storeDsn)
+	if err != nil {
+		log.Fatal(err)
+	}
+	driverStore = ds
+	RegisterService()
+	healthTracker.AddCheck("esr_auth", health.PingCheck(reg, "esr_auth", 2*time.Second))
+	healthTracker.StartKeepalive(serviceName, 30*time.Second)
 	handleRequests()
 }
 
@@ -48,8 +106,45 @@ func main() {
 func GetEnv() {
 	config.apiKey = GetEnvVar("APIKEY")
 	config.authKey = GetEnvVar("AUTHKEY")
-	config.authUrl = GetEnvVar("AUTHURL")
 	config.port = GetEnvVar("PORT")
+	config.addr = GetEnvVar("ADDR")
+	config.registryUrl = GetEnvVar("REGISTRYURL")
+	config.registryFile = os.Getenv("REGISTRYFILE")
+	config.storeKind = GetEnvVar("STORE")
+	config.storeDsn = GetEnvVar("STOREDSN")
+}
+
+// buildRegistry returns an HTTPRegistry talking to esr_registry, or a
+// FallbackRegistry over it and a FileRegistry if REGISTRYFILE is set, so
+// peers stay resolvable if esr_registry itself is unreachable.
+func buildRegistry() registry.Registry {
+	primary := registry.NewHTTPRegistry(config.registryUrl)
+	if config.registryFile == "" {
+		return primary
+	}
+	secondary, err := registry.NewFileRegistry(config.registryFile)
+	if err != nil {
+		log.Print("Warning: could not load registry fallback file: " + err.Error())
+		return primary
+	}
+	return registry.NewFallbackRegistry(primary, secondary)
+}
+
+// Registers this instance with esr_registry, keeps the registration alive
+// with periodic heartbeats, and deregisters it on SIGTERM
+func RegisterService() {
+	reg = buildRegistry()
+	stop := make(chan struct{})
+	if err := registry.KeepAlive(reg, serviceName, config.addr, registrationTtl, stop); err != nil {
+		log.Fatal(err)
+	}
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, syscall.SIGTERM)
+	go func() {
+		<-sigs
+		close(stop)
+		os.Exit(0)
+	}()
 }
 
 // Gets a specific var from env, or log fatal if missing
@@ -70,14 +165,16 @@ func handleRequests() {
 	router.HandleFunc("/esr_drivers", ReadAll).Methods("GET")
 	router.HandleFunc("/esr_drivers", Update).Methods("PUT")
 	router.HandleFunc("/esr_drivers/{user}", Delete).Methods("DELETE")
+	healthTracker.Mount(router)
 	log.Fatal(http.ListenAndServe(":"+config.port, router))
 }
 
 // Handles POST requests, creates a driver and adds them to the roster
 // Request must have a valid x-api-key in header which corresponds to an existing user,
-// and a 'Driver' structured JSOn in the body
+// and a 'Driver' structured body, as JSON, XML or a form
 // Responds with StatusCreated if successful
 func Create(w http.ResponseWriter, r *http.Request) {
+	healthTracker.Inc("requests_handled")
 	// user Api Key authentication
 	var userId string
 	if id, err := Authenticate(r.Header.Get("x-api-key")); err == nil {
@@ -92,15 +189,12 @@ func Create(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	var driver Driver
-	decoder := json.NewDecoder(r.Body)
-	if err := decoder.Decode(&driver); err == nil {
+	if err := bind.Bind(r, &driver); err == nil {
 		if driver.Name != "" {
 			driver.Id = userId
-			index := driver.Name
-			if _, ok := drivers[index]; !ok {
+			if err1 := driverStore.CreateDriver(driver); err1 == nil {
 				w.WriteHeader(http.StatusCreated)
-				drivers[index] = driver
-				log.Print("Created driver: " + index)
+				log.Print("Created driver: " + driver.Name)
 			} else {
 				w.WriteHeader(http.StatusBadRequest)
 			}
@@ -116,21 +210,24 @@ func Create(w http.ResponseWriter, r *http.Request) {
 // Request must have a valid 'x-api-key' in header
 // Responds with the driver corresponding with drivername in request url
 func Read(w http.ResponseWriter, r *http.Request) {
+	healthTracker.Inc("requests_handled")
 	// service Api Key authentication
 	if r.Header.Get("x-api-key") != config.apiKey {
+		healthTracker.Inc("auth_failures")
 		w.WriteHeader(http.StatusBadRequest)
 		return
 	}
 	vars := mux.Vars(r)
 	user := vars["user"]
-	if driver, ok := drivers[user]; ok {
-		if enc, err := json.Marshal(driver); err == nil {
-			w.WriteHeader(http.StatusOK)
-			w.Write([]byte(enc))
-			log.Print("Read driver: " + user)
-		} else {
+	if driver, err := driverStore.GetDriver(user); err == nil {
+		// Negotiate sets Content-Type before writing the body; it must run
+		// before any WriteHeader call, or the header is silently dropped
+		if err1 := bind.Negotiate(w, r, driver); err1 != nil {
 			w.WriteHeader(http.StatusInternalServerError)
+			log.Print(err1)
+			return
 		}
+		log.Print("Read driver: " + user)
 	} else {
 		w.WriteHeader(http.StatusNotFound)
 	}
@@ -140,25 +237,32 @@ func Read(w http.ResponseWriter, r *http.Request) {
 // Request must have a valid 'x-api-key' in header
 // Responds with an array of all drivers currently in the roster
 func ReadAll(w http.ResponseWriter, r *http.Request) {
+	healthTracker.Inc("requests_handled")
 	// service Api Key authentication
 	if r.Header.Get("x-api-key") != config.apiKey {
+		healthTracker.Inc("auth_failures")
 		w.WriteHeader(http.StatusBadRequest)
 		return
 	}
-	if enc, err := json.Marshal(drivers); err == nil {
-		w.Write([]byte(enc))
-		log.Print("Read all drivers")
-	} else {
+	drivers, err := driverStore.ListDrivers()
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	if err1 := bind.Negotiate(w, r, DriverList(drivers)); err1 != nil {
 		w.WriteHeader(http.StatusInternalServerError)
+		return
 	}
+	log.Print("Read all drivers")
 }
 
 // Handles PUT requests, updating driver rates
-// Request must have a 'Driver' structured Json in the body
+// Request must have a 'Driver' structured body, as JSON, XML or a form
 // Only performs the update if the user id from the key matches the id of the
 // driver being edited.a
 // Responds with StatusOK if successful
 func Update(w http.ResponseWriter, r *http.Request) {
+	healthTracker.Inc("requests_handled")
 	// user Api Key authentication
 	var userId string
 	if id, err := Authenticate(r.Header.Get("x-api-key")); err == nil {
@@ -172,14 +276,15 @@ func Update(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	var driver Driver
-	decoder := json.NewDecoder(r.Body)
-	if err := decoder.Decode(&driver); err == nil {
+	if err := bind.Bind(r, &driver); err == nil {
 		driver.Id = userId
-		index := driver.Name
-		if drv, ok := drivers[index]; ok && drv.Id == userId {
-			w.WriteHeader(http.StatusOK)
-			drivers[index] = driver
-			log.Print("Updated driver: " + index)
+		if existing, err1 := driverStore.GetDriver(driver.Name); err1 == nil && existing.Id == userId {
+			if err2 := driverStore.UpdateDriver(driver); err2 == nil {
+				w.WriteHeader(http.StatusOK)
+				log.Print("Updated driver: " + driver.Name)
+			} else {
+				w.WriteHeader(http.StatusBadRequest)
+			}
 		} else {
 			w.WriteHeader(http.StatusBadRequest)
 		}
@@ -193,6 +298,7 @@ func Update(w http.ResponseWriter, r *http.Request) {
 // Only performs the request if the api key corresponds to the user who created the driver
 // Reponds with StatusOK if successful
 func Delete(w http.ResponseWriter, r *http.Request) {
+	healthTracker.Inc("requests_handled")
 	// user Api Key authentication
 	var userId string
 	if id, err := Authenticate(r.Header.Get("x-api-key")); err == nil {
@@ -207,29 +313,43 @@ func Delete(w http.ResponseWriter, r *http.Request) {
 	}
 	vars := mux.Vars(r)
 	user := vars["user"]
-	if driver, ok := drivers[user]; ok && driver.Id == userId {
-		w.WriteHeader(http.StatusOK)
-		delete(drivers, user)
-		log.Print("Deleted driver: " + user)
+	if driver, err := driverStore.GetDriver(user); err == nil && driver.Id == userId {
+		if err1 := driverStore.DeleteDriver(user); err1 == nil {
+			w.WriteHeader(http.StatusOK)
+			log.Print("Deleted driver: " + user)
+		} else {
+			w.WriteHeader(http.StatusBadRequest)
+		}
 	} else {
 		w.WriteHeader(http.StatusBadRequest)
 	}
 }
 
-// Sends request to esr_auth service and returns user ID which matches the key
+// Sends request to an esr_auth instance resolved through the registry and
+// returns the user ID which matches the key
 // Returns empty string if no users match the key
 func Authenticate(key string) (string, error) {
+	endpoints, err := reg.GetService("esr_auth")
+	if err != nil {
+		return "", err
+	}
+	endpoint, err1 := authBalancer.Pick(endpoints)
+	if err1 != nil {
+		return "", err1
+	}
 	client := &http.Client{}
-	url := config.authUrl + "/" + key
+	url := endpoint.Addr + "/esr_auth/" + key
 	if req, err1 := http.NewRequest("GET", url, nil); err1 == nil {
 		req.Header.Set("x-api-key", config.authKey)
 		if resp, err2 := client.Do(req); err2 == nil {
 			if body, err3 := ioutil.ReadAll(resp.Body); err3 == nil {
 				return string(body), nil
 			} else {
+				healthTracker.Inc("upstream_errors")
 				return "", err3
 			}
 		} else {
+			healthTracker.Inc("upstream_errors")
 			return "", err2
 		}
 	} else {
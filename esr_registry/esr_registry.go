@@ -0,0 +1,153 @@
+package main
+
+import (
+	"encoding/json"
+	"github.com/JakubKwak/go-learn-ca/pkg/registry"
+	"github.com/gorilla/mux"
+	"github.com/joho/godotenv"
+	"log"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+// .env config struct
+type Config struct {
+	port string
+}
+
+// instance is one registered endpoint of a service
+type instance struct {
+	registry.Endpoint
+}
+
+// config struct to hold URLs and API keys
+var config Config
+
+// services holds every registered instance, keyed by service name then id
+var services map[string]map[string]instance
+var servicesMutex sync.Mutex
+
+// invoked before main
+func init() {
+	if err := godotenv.Load(); err != nil {
+		log.Fatal("No .env file found")
+	}
+}
+
+func main() {
+	GetEnv()
+	services = make(map[string]map[string]instance)
+	handleRequests()
+}
+
+// Gets environment variables and store them in config struct
+func GetEnv() {
+	config.port = GetEnvVar("PORT")
+}
+
+// Gets a specific var from env, or log fatal if missing
+func GetEnvVar(varName string) string {
+	if value, exists := os.LookupEnv(varName); exists {
+		return value
+	} else {
+		log.Fatal("Error: " + varName + " missing in config")
+		return ""
+	}
+}
+
+// Listens to and handles incoming HTTP requests
+func handleRequests() {
+	router := mux.NewRouter().StrictSlash(true)
+	router.HandleFunc("/services", Register).Methods("POST")
+	router.HandleFunc("/services/{name}/{id}", Deregister).Methods("DELETE")
+	router.HandleFunc("/services/{name}", GetService).Methods("GET")
+	log.Fatal(http.ListenAndServe(":"+config.port, router))
+}
+
+// registerRequest is the body expected by POST /services
+type registerRequest struct {
+	Name string `json:"name"`
+	Addr string `json:"addr"`
+	Ttl  int64  `json:"ttl"`
+}
+
+// registerResponse is the body returned by POST /services
+type registerResponse struct {
+	Id string `json:"id"`
+}
+
+// Handles POST requests, registering a new instance of a service
+// Request must have a 'registerRequest' structured JSON in the body
+// Responds with StatusCreated and the new instance's id if successful
+func Register(w http.ResponseWriter, r *http.Request) {
+	var reg registerRequest
+	decoder := json.NewDecoder(r.Body)
+	if err := decoder.Decode(&reg); err != nil || reg.Name == "" || reg.Addr == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	id := reg.Addr
+	ep := instance{registry.Endpoint{
+		Id:      id,
+		Addr:    reg.Addr,
+		Expires: time.Now().Add(time.Duration(reg.Ttl) * time.Second),
+	}}
+	servicesMutex.Lock()
+	if _, ok := services[reg.Name]; !ok {
+		services[reg.Name] = make(map[string]instance)
+	}
+	services[reg.Name][id] = ep
+	servicesMutex.Unlock()
+	log.Print("Registered service: " + reg.Name + " at " + reg.Addr)
+	if enc, err := json.Marshal(registerResponse{Id: id}); err == nil {
+		w.WriteHeader(http.StatusCreated)
+		w.Write(enc)
+	} else {
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+}
+
+// Handles DELETE requests, removing an instance from a service's roster
+// Responds with StatusOK if successful
+func Deregister(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	name := vars["name"]
+	id := vars["id"]
+	servicesMutex.Lock()
+	defer servicesMutex.Unlock()
+	if instances, ok := services[name]; ok {
+		if _, ok1 := instances[id]; ok1 {
+			delete(instances, id)
+			log.Print("Deregistered service: " + name + " id " + id)
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+	}
+	w.WriteHeader(http.StatusNotFound)
+}
+
+// Handles GET requests, responding with the live (non-expired) endpoints
+// currently registered for the given service name
+func GetService(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	name := vars["name"]
+	now := time.Now()
+	var live []registry.Endpoint
+	servicesMutex.Lock()
+	for id, ep := range services[name] {
+		if ep.Expires.After(now) {
+			live = append(live, ep.Endpoint)
+		} else {
+			delete(services[name], id)
+		}
+	}
+	servicesMutex.Unlock()
+	if enc, err := json.Marshal(live); err == nil {
+		w.WriteHeader(http.StatusOK)
+		w.Write(enc)
+	} else {
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,95 @@
+// Package googlemaps implements domain.RouteProvider against an esr_directions
+// instance, which itself proxies Google's Directions API and returns its
+// JSON shape unchanged.
+package googlemaps
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/JakubKwak/go-learn-ca/esr_riders/domain"
+	"github.com/JakubKwak/go-learn-ca/pkg/health"
+	"github.com/JakubKwak/go-learn-ca/pkg/registry"
+	"net/http"
+	"net/url"
+	"regexp"
+)
+
+// directionsResponse mirrors the JSON shape returned by Google's Directions API
+type directionsResponse struct {
+	Routes []struct {
+		Legs []struct {
+			Distance struct {
+				Value float64 `json:"value"`
+			} `json:"distance"`
+			Steps []struct {
+				Distance struct {
+					Value float64 `json:"value"`
+				} `json:"distance"`
+				HtmlInstructions string `json:"html_instructions"`
+			} `json:"steps"`
+		} `json:"legs"`
+	} `json:"routes"`
+}
+
+// aRoadPattern matches Google's "A1", "A414" etc. instruction references
+var aRoadPattern = regexp.MustCompile(`A(\d)+`)
+
+// RouteProvider is a domain.RouteProvider backed by an esr_directions
+// instance resolved through the registry.
+type RouteProvider struct {
+	reg      registry.Registry
+	balancer *registry.Balancer
+	apiKey   string
+	client   *http.Client
+	health   *health.Health
+}
+
+// NewRouteProvider builds a RouteProvider that resolves esr_directions
+// through reg, spreading requests across instances with balancer.
+func NewRouteProvider(reg registry.Registry, balancer *registry.Balancer, apiKey string, h *health.Health) *RouteProvider {
+	return &RouteProvider{reg: reg, balancer: balancer, apiKey: apiKey, client: &http.Client{}, health: h}
+}
+
+// Route requests the directions for journey from esr_directions and reduces
+// them to the total distance and the portion of it spent on A-roads.
+func (p *RouteProvider) Route(journey domain.Journey) (domain.Route, error) {
+	endpoints, err := p.reg.GetService("esr_directions")
+	if err != nil {
+		return domain.Route{}, err
+	}
+	endpoint, err1 := p.balancer.Pick(endpoints)
+	if err1 != nil {
+		return domain.Route{}, err1
+	}
+	query := url.Values{}
+	query.Set("start", journey.Start)
+	query.Set("end", journey.End)
+	directionsUrl := endpoint.Addr + "/esr_directions?" + query.Encode()
+	var directions directionsResponse
+	if req, err1 := http.NewRequest("GET", directionsUrl, nil); err1 == nil {
+		req.Header.Set("x-api-key", p.apiKey)
+		if resp, err2 := p.client.Do(req); err2 == nil {
+			decoder := json.NewDecoder(resp.Body)
+			if err3 := decoder.Decode(&directions); err3 != nil {
+				p.health.Inc("upstream_errors")
+				return domain.Route{}, errors.New("Error: Response body decoding failed")
+			}
+		} else {
+			p.health.Inc("upstream_errors")
+			return domain.Route{}, errors.New("Error: HTTP request sending gailed")
+		}
+	} else {
+		return domain.Route{}, errors.New("Error: HTTP request creation gailed")
+	}
+	if len(directions.Routes) == 0 || len(directions.Routes[0].Legs) == 0 {
+		return domain.Route{}, domain.ErrNoRoute
+	}
+	leg := directions.Routes[0].Legs[0]
+	aRoadTotal := 0.0
+	for _, step := range leg.Steps {
+		if aRoadPattern.MatchString(step.HtmlInstructions) {
+			aRoadTotal += step.Distance.Value
+		}
+	}
+	return domain.Route{DistanceMeters: leg.Distance.Value, ARoadMeters: aRoadTotal}, nil
+}
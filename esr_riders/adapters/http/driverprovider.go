@@ -0,0 +1,68 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/JakubKwak/go-learn-ca/esr_riders/domain"
+	"github.com/JakubKwak/go-learn-ca/pkg/health"
+	"github.com/JakubKwak/go-learn-ca/pkg/registry"
+	"math"
+	nethttp "net/http"
+)
+
+// DriverProvider is a domain.DriverProvider backed by an esr_drivers
+// instance resolved through the registry.
+type DriverProvider struct {
+	reg      registry.Registry
+	balancer *registry.Balancer
+	apiKey   string
+	client   *nethttp.Client
+	health   *health.Health
+}
+
+// NewDriverProvider builds a DriverProvider that resolves esr_drivers
+// through reg, spreading requests across instances with balancer.
+func NewDriverProvider(reg registry.Registry, balancer *registry.Balancer, apiKey string, h *health.Health) *DriverProvider {
+	return &DriverProvider{reg: reg, balancer: balancer, apiKey: apiKey, client: &nethttp.Client{}, health: h}
+}
+
+// BestDriver requests the current roster from esr_drivers and returns the
+// driver with the lowest rate, or an empty Driver if the roster is empty.
+func (p *DriverProvider) BestDriver() (domain.Driver, int, error) {
+	var driver domain.Driver
+	endpoints, err := p.reg.GetService("esr_drivers")
+	if err != nil {
+		return driver, 1, err
+	}
+	endpoint, err1 := p.balancer.Pick(endpoints)
+	if err1 != nil {
+		return driver, 1, err1
+	}
+	driversUrl := endpoint.Addr + "/esr_drivers"
+	if req, err1 := nethttp.NewRequest("GET", driversUrl, nil); err1 == nil {
+		req.Header.Set("x-api-key", p.apiKey)
+		if resp, err2 := p.client.Do(req); err2 == nil {
+			var drivers map[string]domain.Driver
+			decoder := json.NewDecoder(resp.Body)
+			if err3 := decoder.Decode(&drivers); err3 == nil {
+				// Find lowest rate
+				lowest := math.Inf(1)
+				for _, curDriver := range drivers {
+					if curDriver.Rate < lowest {
+						lowest = curDriver.Rate
+						driver = curDriver
+					}
+				}
+				return driver, len(drivers), nil
+			} else {
+				p.health.Inc("upstream_errors")
+				return driver, 1, errors.New("Error: Response body decoding failed")
+			}
+		} else {
+			p.health.Inc("upstream_errors")
+			return driver, 1, errors.New("Error: HTTP request sending gailed")
+		}
+	} else {
+		return driver, 1, errors.New("Error: HTTP request creation gailed")
+	}
+}
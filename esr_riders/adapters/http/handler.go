@@ -0,0 +1,70 @@
+// Package http wires the domain.PricingEngine up to HTTP: translating the
+// incoming request into a domain.Journey and the domain.Fare back into a
+// response, holding no pricing logic of its own.
+package http
+
+import (
+	"github.com/JakubKwak/go-learn-ca/esr_riders/domain"
+	"github.com/JakubKwak/go-learn-ca/pkg/bind"
+	"github.com/JakubKwak/go-learn-ca/pkg/health"
+	"log"
+	nethttp "net/http"
+)
+
+// Response is the JSON/XML shape returned to riders.
+type Response struct {
+	FinalRate float64
+	Cost      float64
+}
+
+// journeyRequest binds the rider-facing query parameters.
+type journeyRequest struct {
+	Start string `form:"start"`
+	End   string `form:"end"`
+}
+
+// Handler translates HTTP requests into calls against a domain.PricingEngine.
+type Handler struct {
+	engine *domain.PricingEngine
+	health *health.Health
+}
+
+// NewHandler builds a Handler over engine, reporting request counters to h.
+func NewHandler(engine *domain.PricingEngine, h *health.Health) *Handler {
+	return &Handler{engine: engine, health: h}
+}
+
+// RiderRequest handles GET requests, responding with the best driver's final
+// rate and cost for the given journey.
+// The HTTP request must contain 'start' and 'end' query parameters.
+func (h *Handler) RiderRequest(w nethttp.ResponseWriter, r *nethttp.Request) {
+	h.health.Inc("requests_handled")
+	var req journeyRequest
+	if err := bind.Bind(r, &req); err != nil {
+		w.WriteHeader(nethttp.StatusBadRequest)
+		log.Print(err)
+		return
+	}
+	journey := domain.Journey{Start: req.Start, End: req.End}
+	driver, fare, err := h.engine.Price(journey)
+	if err == domain.ErrNoDrivers {
+		// if no driver is available, esr_drivers does not allow empty names so
+		// this can only mean the roster is empty
+		w.Write([]byte("No drivers available at this time."))
+		log.Print("Tried finding best driver, but none available.")
+		return
+	}
+	if err != nil {
+		w.WriteHeader(nethttp.StatusInternalServerError)
+		log.Print(err)
+		return
+	}
+	log.Printf("Calculated journey. Driver: %s, Final Rate: £%.2f/km, Cost: £%.2f", driver.Name, fare.Rate, fare.Cost)
+	response := Response{FinalRate: fare.Rate, Cost: fare.Cost}
+	// Negotiate sets Content-Type before writing the body; it must run
+	// before any WriteHeader call, or the header is silently dropped
+	if err := bind.Negotiate(w, r, response); err != nil {
+		w.WriteHeader(nethttp.StatusInternalServerError)
+		log.Print(err)
+	}
+}
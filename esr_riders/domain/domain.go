@@ -0,0 +1,63 @@
+// Package domain holds the pure pricing rules for esr_riders, free of HTTP
+// clients, the registry, and wall-clock time, so they can be unit tested
+// without standing up any microservice.
+package domain
+
+import (
+	"errors"
+	"time"
+)
+
+// ErrNoRoute is returned when a RouteProvider has no route for a Journey.
+var ErrNoRoute = errors.New("domain: no route found")
+
+// ErrNoDrivers is returned when a DriverProvider has no driver to offer.
+var ErrNoDrivers = errors.New("domain: no drivers available")
+
+// Driver is a driver available to take a journey.
+type Driver struct {
+	Name string
+	Rate float64
+}
+
+// Journey is the requested start and end locations for a ride.
+type Journey struct {
+	Start string
+	End   string
+}
+
+// Route is the distance and road-composition data needed to price a Journey.
+type Route struct {
+	DistanceMeters float64
+	ARoadMeters    float64
+}
+
+// Fare is the priced outcome of a Journey with a given Driver.
+type Fare struct {
+	Rate float64 // final £/km rate after surge multipliers
+	Cost float64 // total cost in GBP
+}
+
+// Clock reports the current time, so PricingEngine never calls time.Now() itself.
+type Clock interface {
+	Now() time.Time
+}
+
+// RealClock is the Clock used in production.
+type RealClock struct{}
+
+// Now returns the current time.
+func (RealClock) Now() time.Time {
+	return time.Now()
+}
+
+// RouteProvider resolves the Route data for a Journey.
+type RouteProvider interface {
+	Route(journey Journey) (Route, error)
+}
+
+// DriverProvider finds the best available Driver and the size of the pool
+// it was chosen from.
+type DriverProvider interface {
+	BestDriver() (Driver, int, error)
+}
@@ -0,0 +1,57 @@
+package domain
+
+// fewDriversThreshold is the pool size below which the low-availability
+// surge multiplier kicks in.
+const fewDriversThreshold = 5
+
+// nightStartHour and nightEndHour bound the night surge window: after
+// nightStartHour or before nightEndHour counts as night.
+const nightStartHour = 22
+const nightEndHour = 6
+
+// PricingEngine prices a Journey by picking the best driver and combining
+// the surge multipliers that apply to the route, the driver pool and the
+// time of day.
+type PricingEngine struct {
+	clock   Clock
+	routes  RouteProvider
+	drivers DriverProvider
+}
+
+// NewPricingEngine builds a PricingEngine from its three ports.
+func NewPricingEngine(clock Clock, routes RouteProvider, drivers DriverProvider) *PricingEngine {
+	return &PricingEngine{clock: clock, routes: routes, drivers: drivers}
+}
+
+// Price picks the best available driver and returns the Fare for journey
+// with them. Returns ErrNoDrivers if no driver is available, or ErrNoRoute
+// if the RouteProvider has no route for the journey.
+func (e *PricingEngine) Price(journey Journey) (Driver, Fare, error) {
+	driver, numDrivers, err := e.drivers.BestDriver()
+	if err != nil {
+		return Driver{}, Fare{}, err
+	}
+	if driver.Name == "" {
+		return Driver{}, Fare{}, ErrNoDrivers
+	}
+	route, err := e.routes.Route(journey)
+	if err != nil {
+		return Driver{}, Fare{}, err
+	}
+	multiplier := 1.0
+	// criteria 1: majority of the route is on an A-road
+	if route.ARoadMeters > route.DistanceMeters/2 {
+		multiplier *= 2
+	}
+	// criteria 2: few drivers available
+	if numDrivers < fewDriversThreshold {
+		multiplier *= 2
+	}
+	// criteria 3: night hours
+	if hour := e.clock.Now().Hour(); hour > nightStartHour || hour < nightEndHour {
+		multiplier *= 2
+	}
+	rate := multiplier * driver.Rate
+	cost := route.DistanceMeters / 1000.0 * rate
+	return driver, Fare{Rate: rate, Cost: cost}, nil
+}
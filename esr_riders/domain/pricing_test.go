@@ -0,0 +1,93 @@
+package domain
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// fakeClock reports a fixed hour, so tests can exercise the night surge
+// window without depending on wall-clock time.
+type fakeClock struct{ hour int }
+
+func (c fakeClock) Now() time.Time {
+	return time.Date(2026, 1, 1, c.hour, 0, 0, 0, time.UTC)
+}
+
+type fakeRouteProvider struct {
+	route Route
+	err   error
+}
+
+func (p fakeRouteProvider) Route(journey Journey) (Route, error) {
+	return p.route, p.err
+}
+
+type fakeDriverProvider struct {
+	driver     Driver
+	numDrivers int
+	err        error
+}
+
+func (p fakeDriverProvider) BestDriver() (Driver, int, error) {
+	return p.driver, p.numDrivers, p.err
+}
+
+func TestPricingEngine_Price(t *testing.T) {
+	tests := []struct {
+		name     string
+		clock    fakeClock
+		routes   fakeRouteProvider
+		drivers  fakeDriverProvider
+		wantFare Fare
+		wantErr  error
+	}{
+		{
+			name:     "night surge with few drivers and A-road majority stacks all three multipliers",
+			clock:    fakeClock{hour: 23},
+			routes:   fakeRouteProvider{route: Route{DistanceMeters: 10000, ARoadMeters: 6000}},
+			drivers:  fakeDriverProvider{driver: Driver{Name: "Alice", Rate: 1}, numDrivers: 2},
+			wantFare: Fare{Rate: 8, Cost: 80},
+		},
+		{
+			name:     "daytime, plenty of drivers and no A-roads applies no multiplier",
+			clock:    fakeClock{hour: 12},
+			routes:   fakeRouteProvider{route: Route{DistanceMeters: 10000, ARoadMeters: 0}},
+			drivers:  fakeDriverProvider{driver: Driver{Name: "Bob", Rate: 2}, numDrivers: 10},
+			wantFare: Fare{Rate: 2, Cost: 20},
+		},
+		{
+			name:    "no route for the journey surfaces ErrNoRoute",
+			clock:   fakeClock{hour: 12},
+			routes:  fakeRouteProvider{err: ErrNoRoute},
+			drivers: fakeDriverProvider{driver: Driver{Name: "Bob", Rate: 2}, numDrivers: 10},
+			wantErr: ErrNoRoute,
+		},
+		{
+			name:    "empty driver pool surfaces ErrNoDrivers",
+			clock:   fakeClock{hour: 12},
+			routes:  fakeRouteProvider{route: Route{DistanceMeters: 10000}},
+			drivers: fakeDriverProvider{driver: Driver{}, numDrivers: 0},
+			wantErr: ErrNoDrivers,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			engine := NewPricingEngine(tt.clock, tt.routes, tt.drivers)
+			_, fare, err := engine.Price(Journey{Start: "a", End: "b"})
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("got err %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if fare != tt.wantFare {
+				t.Fatalf("got fare %+v, want %+v", fare, tt.wantFare)
+			}
+		})
+	}
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"encoding/json"
+	"github.com/JakubKwak/go-learn-ca/pkg/registry"
+	"github.com/gorilla/mux"
+	"github.com/joho/godotenv"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// .env config struct
+type Config struct {
+	port         string
+	registryUrl  string
+	registryFile string
+	services     []string
+	pollInterval time.Duration
+}
+
+// config struct to hold URLs and API keys
+var config Config
+
+// reg is the registry client used to resolve each monitored service
+var reg registry.Registry
+
+// serviceStatus is the last observed readiness of one monitored service
+type serviceStatus struct {
+	Ready   bool      `json:"ready"`
+	Detail  string    `json:"detail"`
+	Checked time.Time `json:"checked"`
+}
+
+// statuses holds the latest serviceStatus for every monitored service, keyed by name
+var statuses map[string]serviceStatus
+var statusesMutex sync.RWMutex
+
+// invoked before main
+func init() {
+	if err := godotenv.Load(); err != nil {
+		log.Fatal("No .env file found")
+	}
+}
+
+func main() {
+	GetEnv()
+	reg = buildRegistry()
+	statuses = make(map[string]serviceStatus)
+	go pollLoop()
+	handleRequests()
+}
+
+// Gets environment variables and store them in config struct
+func GetEnv() {
+	config.port = GetEnvVar("PORT")
+	config.registryUrl = GetEnvVar("REGISTRYURL")
+	config.registryFile = os.Getenv("REGISTRYFILE")
+	config.services = strings.Split(GetEnvVar("SERVICES"), ",")
+	seconds := GetEnvVar("POLLINTERVAL")
+	if n, err := time.ParseDuration(seconds + "s"); err == nil {
+		config.pollInterval = n
+	} else {
+		log.Fatal("Error: POLLINTERVAL missing or invalid in config")
+	}
+}
+
+// buildRegistry returns an HTTPRegistry talking to esr_registry, or a
+// FallbackRegistry over it and a FileRegistry if REGISTRYFILE is set, so
+// peers stay resolvable if esr_registry itself is unreachable.
+func buildRegistry() registry.Registry {
+	primary := registry.NewHTTPRegistry(config.registryUrl)
+	if config.registryFile == "" {
+		return primary
+	}
+	secondary, err := registry.NewFileRegistry(config.registryFile)
+	if err != nil {
+		log.Print("Warning: could not load registry fallback file: " + err.Error())
+		return primary
+	}
+	return registry.NewFallbackRegistry(primary, secondary)
+}
+
+// Gets a specific var from env, or log fatal if missing
+func GetEnvVar(varName string) string {
+	if value, exists := os.LookupEnv(varName); exists {
+		return value
+	} else {
+		log.Fatal("Error: " + varName + " missing in config")
+		return ""
+	}
+}
+
+// Listens to and handles incoming HTTP requests
+func handleRequests() {
+	router := mux.NewRouter().StrictSlash(true)
+	router.HandleFunc("/status", GetStatus).Methods("GET")
+	log.Fatal(http.ListenAndServe(":"+config.port, router))
+}
+
+// pollLoop periodically checks /readyz on every configured service and
+// records the result, so GetStatus can answer without blocking on a live poll
+func pollLoop() {
+	client := &http.Client{Timeout: 2 * time.Second}
+	poll := func() {
+		for _, name := range config.services {
+			status := pollOne(client, name)
+			statusesMutex.Lock()
+			statuses[name] = status
+			statusesMutex.Unlock()
+		}
+	}
+	poll()
+	for range time.Tick(config.pollInterval) {
+		poll()
+	}
+}
+
+// pollOne resolves name through the registry and checks its /readyz endpoint
+func pollOne(client *http.Client, name string) serviceStatus {
+	now := time.Now()
+	endpoints, err := reg.GetService(name)
+	if err != nil {
+		return serviceStatus{Ready: false, Detail: err.Error(), Checked: now}
+	}
+	if len(endpoints) == 0 {
+		return serviceStatus{Ready: false, Detail: "no live endpoints", Checked: now}
+	}
+	resp, err1 := client.Get(endpoints[0].Addr + "/readyz")
+	if err1 != nil {
+		return serviceStatus{Ready: false, Detail: err1.Error(), Checked: now}
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return serviceStatus{Ready: false, Detail: resp.Status, Checked: now}
+	}
+	return serviceStatus{Ready: true, Detail: "ok", Checked: now}
+}
+
+// Handles GET requests, responding with the latest observed status of every
+// monitored service
+func GetStatus(w http.ResponseWriter, r *http.Request) {
+	statusesMutex.RLock()
+	snapshot := make(map[string]serviceStatus, len(statuses))
+	for name, status := range statuses {
+		snapshot[name] = status
+	}
+	statusesMutex.RUnlock()
+	if enc, err := json.Marshal(snapshot); err == nil {
+		w.WriteHeader(http.StatusOK)
+		w.Write(enc)
+	} else {
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,136 @@
+// Package bind provides content-type-aware request binding for the esr
+// services, so handlers stop assuming every caller speaks raw JSON.
+package bind
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"mime"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strconv"
+)
+
+// BindError is returned by Bind when a request cannot be decoded into the
+// target value. It always maps to an HTTP 400 response.
+type BindError struct {
+	Reason string
+	Err    error
+}
+
+func (e *BindError) Error() string {
+	if e.Err != nil {
+		return e.Reason + ": " + e.Err.Error()
+	}
+	return e.Reason
+}
+
+func (e *BindError) Unwrap() error { return e.Err }
+
+// StatusCode is always http.StatusBadRequest: a bind failure means the
+// caller sent something this service cannot understand.
+func (e *BindError) StatusCode() int { return http.StatusBadRequest }
+
+// Bind decodes r into v, modelled on Echo's default binder. GET and DELETE
+// requests are bound from the query string via `form:"..."` struct tags
+// (falling back to the field name). Every other method is bound from the
+// body according to Content-Type: application/json, application/xml or
+// text/xml, and application/x-www-form-urlencoded or multipart/form-data.
+func Bind(r *http.Request, v interface{}) error {
+	if r.Method == http.MethodGet || r.Method == http.MethodDelete {
+		return bindValues(r.URL.Query(), v)
+	}
+	contentType := r.Header.Get("Content-Type")
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		mediaType = contentType
+	}
+	switch mediaType {
+	case "application/json", "":
+		return bindJSON(r, v)
+	case "application/xml", "text/xml":
+		return bindXML(r, v)
+	case "application/x-www-form-urlencoded":
+		if err1 := r.ParseForm(); err1 != nil {
+			return &BindError{Reason: "Could not parse form body", Err: err1}
+		}
+		return bindValues(r.Form, v)
+	case "multipart/form-data":
+		if err1 := r.ParseMultipartForm(32 << 20); err1 != nil {
+			return &BindError{Reason: "Could not parse multipart form body", Err: err1}
+		}
+		return bindValues(r.Form, v)
+	default:
+		return &BindError{Reason: "Unsupported Content-Type: " + contentType}
+	}
+}
+
+func bindJSON(r *http.Request, v interface{}) error {
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		return &BindError{Reason: "Could not decode JSON body", Err: err}
+	}
+	return nil
+}
+
+func bindXML(r *http.Request, v interface{}) error {
+	if err := xml.NewDecoder(r.Body).Decode(v); err != nil {
+		return &BindError{Reason: "Could not decode XML body", Err: err}
+	}
+	return nil
+}
+
+// bindValues copies values into v's fields by `form` tag, falling back to
+// the field name, and is used for both query strings and form bodies.
+func bindValues(values url.Values, v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.Elem().Kind() != reflect.Struct {
+		return &BindError{Reason: "Bind target must be a pointer to a struct"}
+	}
+	elem := rv.Elem()
+	t := elem.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name := field.Tag.Get("form")
+		if name == "" {
+			name = field.Name
+		}
+		value := values.Get(name)
+		if value == "" {
+			continue
+		}
+		if err := setField(elem.Field(i), value); err != nil {
+			return &BindError{Reason: "Could not bind field " + field.Name, Err: err}
+		}
+	}
+	return nil
+}
+
+func setField(field reflect.Value, value string) error {
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(value)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return err
+		}
+		field.SetFloat(f)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return err
+		}
+		field.SetInt(n)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+		field.SetBool(b)
+	default:
+		return errors.New("bind: unsupported field kind: " + field.Kind().String())
+	}
+	return nil
+}
@@ -0,0 +1,30 @@
+package bind
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"net/http"
+	"strings"
+)
+
+// Negotiate marshals v according to the caller's Accept header, defaulting
+// to JSON, and writes it to w with a matching Content-Type.
+func Negotiate(w http.ResponseWriter, r *http.Request, v interface{}) error {
+	accept := r.Header.Get("Accept")
+	if strings.Contains(accept, "application/xml") || strings.Contains(accept, "text/xml") {
+		enc, err := xml.Marshal(v)
+		if err != nil {
+			return err
+		}
+		w.Header().Set("Content-Type", "application/xml")
+		_, err1 := w.Write(enc)
+		return err1
+	}
+	enc, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_, err1 := w.Write(enc)
+	return err1
+}
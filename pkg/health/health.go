@@ -0,0 +1,139 @@
+// Package health gives every esr service a uniform /healthz, /readyz and
+// /metrics surface, replacing the "log.Fatal on startup and hope" model with
+// something an operator can actually poll.
+package health
+
+import (
+	"encoding/json"
+	"github.com/gorilla/mux"
+	"log"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Checker reports whether a downstream dependency is reachable.
+type Checker func() error
+
+// Health tracks readiness checks and rolling counters for a single service.
+type Health struct {
+	mu       sync.RWMutex
+	checks   map[string]Checker
+	counters map[string]*int64
+	lastPing int64 // unix seconds of the last successful Ping
+}
+
+// New builds an empty Health tracker.
+func New() *Health {
+	return &Health{
+		checks:   make(map[string]Checker),
+		counters: make(map[string]*int64),
+	}
+}
+
+// AddCheck registers a readiness check under name, run on every /readyz call.
+func (h *Health) AddCheck(name string, check Checker) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.checks[name] = check
+}
+
+// Inc increments the named counter, e.g. "requests_handled" or "auth_failures".
+func (h *Health) Inc(counter string) {
+	h.mu.Lock()
+	c, ok := h.counters[counter]
+	if !ok {
+		c = new(int64)
+		h.counters[counter] = c
+	}
+	h.mu.Unlock()
+	atomic.AddInt64(c, 1)
+}
+
+// Ping records a successful upstream ping, surfaced by /metrics as last_ping_unix.
+func (h *Health) Ping() {
+	atomic.StoreInt64(&h.lastPing, time.Now().Unix())
+}
+
+// Mount wires /healthz, /readyz and /metrics onto router.
+func (h *Health) Mount(router *mux.Router) {
+	router.HandleFunc("/healthz", h.handleLiveness).Methods("GET")
+	router.HandleFunc("/readyz", h.handleReadiness).Methods("GET")
+	router.HandleFunc("/metrics", h.handleMetrics).Methods("GET")
+}
+
+// handleLiveness always answers 200: if the process can handle HTTP at all, it's alive.
+func (h *Health) handleLiveness(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("ok"))
+}
+
+// handleReadiness runs every registered check and reports 503 if any fail.
+func (h *Health) handleReadiness(w http.ResponseWriter, r *http.Request) {
+	h.mu.RLock()
+	checks := make(map[string]Checker, len(h.checks))
+	for name, check := range h.checks {
+		checks[name] = check
+	}
+	h.mu.RUnlock()
+	results := make(map[string]string, len(checks))
+	ready := true
+	for name, check := range checks {
+		if err := check(); err != nil {
+			results[name] = err.Error()
+			ready = false
+		} else {
+			results[name] = "ok"
+		}
+	}
+	if ready {
+		h.Ping()
+	}
+	enc, err := json.Marshal(results)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	if !ready {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	w.Write(enc)
+}
+
+// metrics is the JSON shape returned by /metrics.
+type metrics struct {
+	Counters     map[string]int64 `json:"counters"`
+	LastPingUnix int64            `json:"last_ping_unix"`
+}
+
+func (h *Health) handleMetrics(w http.ResponseWriter, r *http.Request) {
+	h.mu.RLock()
+	counters := make(map[string]int64, len(h.counters))
+	for name, c := range h.counters {
+		counters[name] = atomic.LoadInt64(c)
+	}
+	h.mu.RUnlock()
+	m := metrics{Counters: counters, LastPingUnix: atomic.LoadInt64(&h.lastPing)}
+	if enc, err := json.Marshal(m); err == nil {
+		w.Write(enc)
+	} else {
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+}
+
+// StartKeepalive logs a snapshot of the counters every interval, so a
+// rolling count shows up in the logs even without scraping /metrics.
+func (h *Health) StartKeepalive(serviceName string, interval time.Duration) {
+	go func() {
+		for range time.Tick(interval) {
+			h.mu.RLock()
+			counters := make(map[string]int64, len(h.counters))
+			for name, c := range h.counters {
+				counters[name] = atomic.LoadInt64(c)
+			}
+			h.mu.RUnlock()
+			log.Printf("keepalive: %s counters=%v", serviceName, counters)
+		}
+	}()
+}
@@ -0,0 +1,33 @@
+package health
+
+import (
+	"errors"
+	"github.com/JakubKwak/go-learn-ca/pkg/registry"
+	"net/http"
+	"time"
+)
+
+// PingCheck returns a Checker that resolves name through reg and makes a
+// short-timeout GET against its /healthz endpoint. Used to wire a service's
+// /readyz to the liveness of the peers it actually depends on.
+func PingCheck(reg registry.Registry, name string, timeout time.Duration) Checker {
+	client := &http.Client{Timeout: timeout}
+	return func() error {
+		endpoints, err := reg.GetService(name)
+		if err != nil {
+			return err
+		}
+		if len(endpoints) == 0 {
+			return errors.New("health: no live endpoints for " + name)
+		}
+		resp, err1 := client.Get(endpoints[0].Addr + "/healthz")
+		if err1 != nil {
+			return err1
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return errors.New("health: " + name + " returned " + resp.Status)
+		}
+		return nil
+	}
+}
@@ -0,0 +1,22 @@
+package registry
+
+import (
+	"errors"
+	"sync/atomic"
+)
+
+// Balancer picks one endpoint out of a list returned by GetService, spreading
+// load across every live instance of a service instead of always hitting
+// the first one.
+type Balancer struct {
+	counter uint64
+}
+
+// Pick returns the next endpoint in round-robin order.
+func (b *Balancer) Pick(endpoints []Endpoint) (Endpoint, error) {
+	if len(endpoints) == 0 {
+		return Endpoint{}, errors.New("Error: No endpoints available")
+	}
+	i := atomic.AddUint64(&b.counter, 1)
+	return endpoints[int(i-1)%len(endpoints)], nil
+}
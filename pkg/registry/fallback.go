@@ -0,0 +1,45 @@
+package registry
+
+import "time"
+
+// FallbackRegistry resolves services through a primary Registry (normally
+// an HTTPRegistry talking to esr_registry), falling back to a secondary
+// Registry (normally a FileRegistry of known addresses) whenever the
+// primary errors, so peers stay resolvable if esr_registry itself is
+// unreachable.
+type FallbackRegistry struct {
+	Primary   Registry
+	Secondary Registry
+}
+
+// NewFallbackRegistry builds a FallbackRegistry over primary and secondary.
+func NewFallbackRegistry(primary, secondary Registry) *FallbackRegistry {
+	return &FallbackRegistry{Primary: primary, Secondary: secondary}
+}
+
+// Register always goes through the primary: a static fallback has no way
+// to accept new registrations.
+func (f *FallbackRegistry) Register(name, addr string, ttl time.Duration) (string, error) {
+	return f.Primary.Register(name, addr, ttl)
+}
+
+// Deregister always goes through the primary, for the same reason as Register.
+func (f *FallbackRegistry) Deregister(name, id string) error {
+	return f.Primary.Deregister(name, id)
+}
+
+// GetService tries the primary first and falls back to the secondary if it errors.
+func (f *FallbackRegistry) GetService(name string) ([]Endpoint, error) {
+	if endpoints, err := f.Primary.GetService(name); err == nil {
+		return endpoints, nil
+	}
+	return f.Secondary.GetService(name)
+}
+
+// Watch tries the primary first and falls back to the secondary if it errors.
+func (f *FallbackRegistry) Watch(name string) (<-chan []Endpoint, error) {
+	if ch, err := f.Primary.Watch(name); err == nil {
+		return ch, nil
+	}
+	return f.Secondary.Watch(name)
+}
@@ -0,0 +1,85 @@
+package registry
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"sync"
+	"time"
+)
+
+// FileRegistry is a static Registry fallback for when esr_registry is
+// unreachable. It reads a flat JSON file of the form
+// {"esr_drivers": ["http://10.0.0.1:5432"], "esr_auth": ["http://10.0.0.2:5433"]}
+// and never changes at runtime, so Register/Deregister return an error: the file
+// is meant to be deployed alongside the services, not written to by them.
+type FileRegistry struct {
+	mu   sync.RWMutex
+	path string
+	data map[string][]string
+}
+
+// NewFileRegistry loads the service list from path.
+func NewFileRegistry(path string) (*FileRegistry, error) {
+	fr := &FileRegistry{path: path}
+	if err := fr.reload(); err != nil {
+		return nil, err
+	}
+	return fr, nil
+}
+
+func (f *FileRegistry) reload() error {
+	body, err := ioutil.ReadFile(f.path)
+	if err != nil {
+		return errors.New("Error: Could not read registry file")
+	}
+	var data map[string][]string
+	if err1 := json.Unmarshal(body, &data); err1 != nil {
+		return errors.New("Error: Could not decode registry file")
+	}
+	f.mu.Lock()
+	f.data = data
+	f.mu.Unlock()
+	return nil
+}
+
+// Register is unsupported on a static file fallback.
+func (f *FileRegistry) Register(name, addr string, ttl time.Duration) (string, error) {
+	return "", errors.New("Error: FileRegistry does not support Register")
+}
+
+// Deregister is unsupported on a static file fallback.
+func (f *FileRegistry) Deregister(name, id string) error {
+	return errors.New("Error: FileRegistry does not support Deregister")
+}
+
+// GetService returns every address listed for name in the file.
+func (f *FileRegistry) GetService(name string) ([]Endpoint, error) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	addrs, ok := f.data[name]
+	if !ok {
+		return nil, errors.New("Error: No such service in registry file: " + name)
+	}
+	endpoints := make([]Endpoint, len(addrs))
+	for i, addr := range addrs {
+		endpoints[i] = Endpoint{Id: name, Addr: addr}
+	}
+	return endpoints, nil
+}
+
+// Watch re-reads the file once and returns its current contents; the file
+// is static so there is nothing further to push.
+func (f *FileRegistry) Watch(name string) (<-chan []Endpoint, error) {
+	if err := f.reload(); err != nil {
+		return nil, err
+	}
+	endpoints, err := f.GetService(name)
+	if err != nil {
+		return nil, err
+	}
+	ch := make(chan []Endpoint, 1)
+	ch <- endpoints
+	close(ch)
+	return ch, nil
+}
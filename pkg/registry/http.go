@@ -0,0 +1,124 @@
+package registry
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"time"
+)
+
+// HTTPRegistry is a Registry backed by the esr_registry service.
+type HTTPRegistry struct {
+	baseUrl string
+	client  *http.Client
+}
+
+// NewHTTPRegistry builds a Registry that talks to the esr_registry service at baseUrl.
+func NewHTTPRegistry(baseUrl string) *HTTPRegistry {
+	return &HTTPRegistry{baseUrl: baseUrl, client: &http.Client{Timeout: 5 * time.Second}}
+}
+
+// registerRequest is the body sent to esr_registry's POST /services
+type registerRequest struct {
+	Name string `json:"name"`
+	Addr string `json:"addr"`
+	Ttl  int64  `json:"ttl"`
+}
+
+// registerResponse is the body returned by esr_registry's POST /services
+type registerResponse struct {
+	Id string `json:"id"`
+}
+
+// Register calls POST /services on the registry service.
+func (r *HTTPRegistry) Register(name, addr string, ttl time.Duration) (string, error) {
+	body, err := json.Marshal(registerRequest{Name: name, Addr: addr, Ttl: int64(ttl.Seconds())})
+	if err != nil {
+		return "", errors.New("Error: Could not create JSON from register request")
+	}
+	req, err1 := http.NewRequest("POST", r.baseUrl+"/services", bytes.NewBuffer(body))
+	if err1 != nil {
+		return "", errors.New("Error: HTTP request creation failed")
+	}
+	resp, err2 := r.client.Do(req)
+	if err2 != nil {
+		return "", errors.New("Error: HTTP request sending failed")
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated {
+		return "", errors.New("Error: Registration rejected by registry")
+	}
+	var regResp registerResponse
+	decoder := json.NewDecoder(resp.Body)
+	if err3 := decoder.Decode(&regResp); err3 != nil {
+		return "", errors.New("Error: Response body decoding failed")
+	}
+	return regResp.Id, nil
+}
+
+// Deregister calls DELETE /services/{name}/{id} on the registry service.
+func (r *HTTPRegistry) Deregister(name, id string) error {
+	req, err := http.NewRequest("DELETE", r.baseUrl+"/services/"+name+"/"+id, nil)
+	if err != nil {
+		return errors.New("Error: HTTP request creation failed")
+	}
+	resp, err1 := r.client.Do(req)
+	if err1 != nil {
+		return errors.New("Error: HTTP request sending failed")
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return errors.New("Error: Deregistration rejected by registry")
+	}
+	return nil
+}
+
+// GetService calls GET /services/{name} on the registry service.
+func (r *HTTPRegistry) GetService(name string) ([]Endpoint, error) {
+	resp, err := r.client.Get(r.baseUrl + "/services/" + name)
+	if err != nil {
+		return nil, errors.New("Error: HTTP request sending failed")
+	}
+	defer resp.Body.Close()
+	body, err1 := ioutil.ReadAll(resp.Body)
+	if err1 != nil {
+		return nil, errors.New("Error: Response body reading failed")
+	}
+	var endpoints []Endpoint
+	if err2 := json.Unmarshal(body, &endpoints); err2 != nil {
+		return nil, errors.New("Error: Response body decoding failed")
+	}
+	return endpoints, nil
+}
+
+// Watch polls GetService every watchInterval and pushes the result whenever
+// the set of endpoints changes. The esr_registry service has no push/long-poll
+// support, so polling is the simplest thing that fits this interface.
+const watchInterval = 5 * time.Second
+
+func (r *HTTPRegistry) Watch(name string) (<-chan []Endpoint, error) {
+	endpoints, err := r.GetService(name)
+	if err != nil {
+		return nil, err
+	}
+	ch := make(chan []Endpoint, 1)
+	ch <- endpoints
+	go func() {
+		defer close(ch)
+		last, _ := json.Marshal(endpoints)
+		for range time.Tick(watchInterval) {
+			cur, err := r.GetService(name)
+			if err != nil {
+				continue
+			}
+			enc, _ := json.Marshal(cur)
+			if !bytes.Equal(enc, last) {
+				last = enc
+				ch <- cur
+			}
+		}
+	}()
+	return ch, nil
+}
@@ -0,0 +1,35 @@
+package registry
+
+import (
+	"log"
+	"time"
+)
+
+// KeepAlive registers addr under name and re-registers it every ttl/3 so the
+// registry never lets the entry expire, until stop is closed, at which point
+// it deregisters the instance. Intended to be called once from a service's
+// main() right before it starts serving requests.
+func KeepAlive(reg Registry, name, addr string, ttl time.Duration, stop <-chan struct{}) error {
+	id, err := reg.Register(name, addr, ttl)
+	if err != nil {
+		return err
+	}
+	go func() {
+		ticker := time.NewTicker(ttl / 3)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				if _, err1 := reg.Register(name, addr, ttl); err1 != nil {
+					log.Print(err1)
+				}
+			case <-stop:
+				if err2 := reg.Deregister(name, id); err2 != nil {
+					log.Print(err2)
+				}
+				return
+			}
+		}
+	}()
+	return nil
+}
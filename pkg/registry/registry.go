@@ -0,0 +1,29 @@
+// Package registry provides service discovery for the esr microservices.
+// Instead of every service reading a peer's address out of a fixed env var,
+// services register themselves with a registry and look each other up by
+// name, getting back one or more live endpoints to load balance across.
+package registry
+
+import "time"
+
+// Endpoint is a single live instance of a named service.
+type Endpoint struct {
+	Id      string    `json:"id"`
+	Addr    string    `json:"addr"`
+	Expires time.Time `json:"expires"`
+}
+
+// Registry is the client-side view of service discovery. Implementations
+// back it with different transports (HTTP registry service, static file, ...).
+type Registry interface {
+	// Register advertises addr under name for ttl, returning an id that
+	// must be used to deregister or re-register (heartbeat) it later.
+	Register(name, addr string, ttl time.Duration) (string, error)
+	// Deregister removes the endpoint id previously returned by Register.
+	Deregister(name, id string) error
+	// GetService returns the currently live endpoints for name.
+	GetService(name string) ([]Endpoint, error)
+	// Watch returns a channel that receives the current endpoint list for
+	// name whenever it changes. The channel is closed if watching stops.
+	Watch(name string) (<-chan []Endpoint, error)
+}
@@ -0,0 +1,135 @@
+package store
+
+import (
+	"encoding/json"
+	"github.com/dgraph-io/badger/v3"
+)
+
+// BadgerDriverStore persists the roster in an embedded BadgerDB, so it
+// survives restarts without needing a separate database process - a good
+// fit for a single-node microservice like esr_drivers.
+type BadgerDriverStore struct {
+	db *badger.DB
+}
+
+// NewBadgerDriverStore opens (creating if needed) a BadgerDB at dir.
+func NewBadgerDriverStore(dir string) (*BadgerDriverStore, error) {
+	db, err := badger.Open(badger.DefaultOptions(dir))
+	if err != nil {
+		return nil, err
+	}
+	return &BadgerDriverStore{db: db}, nil
+}
+
+// getDriver reads name within an already-open transaction, so callers can
+// check-then-write atomically instead of racing a separate View/Update pair.
+func getDriver(txn *badger.Txn, name string) (Driver, error) {
+	var driver Driver
+	item, err := txn.Get([]byte(name))
+	if err == badger.ErrKeyNotFound {
+		return driver, ErrNotFound
+	} else if err != nil {
+		return driver, err
+	}
+	err = item.Value(func(val []byte) error {
+		return json.Unmarshal(val, &driver)
+	})
+	return driver, err
+}
+
+func (s *BadgerDriverStore) CreateDriver(driver Driver) error {
+	enc, err := json.Marshal(driver)
+	if err != nil {
+		return err
+	}
+	return s.db.Update(func(txn *badger.Txn) error {
+		if _, err1 := getDriver(txn, driver.Name); err1 == nil {
+			return ErrAlreadyExists
+		} else if err1 != ErrNotFound {
+			return err1
+		}
+		return txn.Set([]byte(driver.Name), enc)
+	})
+}
+
+func (s *BadgerDriverStore) GetDriver(name string) (Driver, error) {
+	var driver Driver
+	err := s.db.View(func(txn *badger.Txn) error {
+		var err1 error
+		driver, err1 = getDriver(txn, name)
+		return err1
+	})
+	return driver, err
+}
+
+func (s *BadgerDriverStore) ListDrivers() (map[string]Driver, error) {
+	drivers := make(map[string]Driver)
+	err := s.db.View(func(txn *badger.Txn) error {
+		it := txn.NewIterator(badger.DefaultIteratorOptions)
+		defer it.Close()
+		for it.Rewind(); it.Valid(); it.Next() {
+			var driver Driver
+			if err1 := it.Item().Value(func(val []byte) error {
+				return json.Unmarshal(val, &driver)
+			}); err1 != nil {
+				return err1
+			}
+			drivers[driver.Name] = driver
+		}
+		return nil
+	})
+	return drivers, err
+}
+
+func (s *BadgerDriverStore) UpdateDriver(driver Driver) error {
+	enc, err := json.Marshal(driver)
+	if err != nil {
+		return err
+	}
+	return s.db.Update(func(txn *badger.Txn) error {
+		if _, err1 := getDriver(txn, driver.Name); err1 != nil {
+			return err1
+		}
+		return txn.Set([]byte(driver.Name), enc)
+	})
+}
+
+func (s *BadgerDriverStore) DeleteDriver(name string) error {
+	return s.db.Update(func(txn *badger.Txn) error {
+		if _, err := getDriver(txn, name); err != nil {
+			return err
+		}
+		return txn.Delete([]byte(name))
+	})
+}
+
+// BadgerUserStore resolves esr_auth API keys to user ids from an embedded
+// BadgerDB, keyed by key.
+type BadgerUserStore struct {
+	db *badger.DB
+}
+
+// NewBadgerUserStore opens (creating if needed) a BadgerDB at dir.
+func NewBadgerUserStore(dir string) (*BadgerUserStore, error) {
+	db, err := badger.Open(badger.DefaultOptions(dir))
+	if err != nil {
+		return nil, err
+	}
+	return &BadgerUserStore{db: db}, nil
+}
+
+func (s *BadgerUserStore) LookupByKey(key string) (User, error) {
+	var user User
+	err := s.db.View(func(txn *badger.Txn) error {
+		item, err1 := txn.Get([]byte(key))
+		if err1 == badger.ErrKeyNotFound {
+			return ErrNotFound
+		} else if err1 != nil {
+			return err1
+		}
+		return item.Value(func(val []byte) error {
+			return json.Unmarshal(val, &user)
+		})
+	})
+	return user, err
+}
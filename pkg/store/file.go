@@ -0,0 +1,55 @@
+package store
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"sync"
+)
+
+// FileUserStore indexes users from a JSON file in the original esr_auth
+// format ([]User) once at startup, instead of reading and linearly
+// scanning the file on every request.
+type FileUserStore struct {
+	mu    sync.RWMutex
+	path  string
+	byKey map[string]User
+}
+
+// NewFileUserStore loads and indexes the users at path.
+func NewFileUserStore(path string) (*FileUserStore, error) {
+	s := &FileUserStore{path: path}
+	if err := s.reload(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *FileUserStore) reload() error {
+	body, err := ioutil.ReadFile(s.path)
+	if err != nil {
+		return err
+	}
+	var users []User
+	if err1 := json.Unmarshal(body, &users); err1 != nil {
+		return err1
+	}
+	byKey := make(map[string]User, len(users))
+	for _, user := range users {
+		byKey[user.Key] = user
+	}
+	s.mu.Lock()
+	s.byKey = byKey
+	s.mu.Unlock()
+	return nil
+}
+
+// LookupByKey returns the user whose Key matches key, in O(1).
+func (s *FileUserStore) LookupByKey(key string) (User, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	user, ok := s.byKey[key]
+	if !ok {
+		return User{}, ErrNotFound
+	}
+	return user, nil
+}
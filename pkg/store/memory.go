@@ -0,0 +1,67 @@
+package store
+
+import "sync"
+
+// MemoryDriverStore is a process-local, non-persistent DriverStore. It
+// matches esr_drivers' original behaviour (a map that dies on restart) but
+// guards the map with a mutex, since it is now reachable from concurrent
+// POST/PUT/DELETE handlers.
+type MemoryDriverStore struct {
+	mu      sync.RWMutex
+	drivers map[string]Driver
+}
+
+// NewMemoryDriverStore builds an empty MemoryDriverStore.
+func NewMemoryDriverStore() *MemoryDriverStore {
+	return &MemoryDriverStore{drivers: make(map[string]Driver)}
+}
+
+func (s *MemoryDriverStore) CreateDriver(driver Driver) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.drivers[driver.Name]; ok {
+		return ErrAlreadyExists
+	}
+	s.drivers[driver.Name] = driver
+	return nil
+}
+
+func (s *MemoryDriverStore) GetDriver(name string) (Driver, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	driver, ok := s.drivers[name]
+	if !ok {
+		return Driver{}, ErrNotFound
+	}
+	return driver, nil
+}
+
+func (s *MemoryDriverStore) ListDrivers() (map[string]Driver, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	out := make(map[string]Driver, len(s.drivers))
+	for name, driver := range s.drivers {
+		out[name] = driver
+	}
+	return out, nil
+}
+
+func (s *MemoryDriverStore) UpdateDriver(driver Driver) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.drivers[driver.Name]; !ok {
+		return ErrNotFound
+	}
+	s.drivers[driver.Name] = driver
+	return nil
+}
+
+func (s *MemoryDriverStore) DeleteDriver(name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.drivers[name]; !ok {
+		return ErrNotFound
+	}
+	delete(s.drivers, name)
+	return nil
+}
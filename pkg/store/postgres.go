@@ -0,0 +1,134 @@
+package store
+
+import (
+	"database/sql"
+	_ "github.com/lib/pq"
+)
+
+// createDriversTable sets up the drivers table with a unique index on name
+// (its lookup key) so reads and writes stay fast as the roster grows.
+const createDriversTable = `
+CREATE TABLE IF NOT EXISTS drivers (
+	name TEXT PRIMARY KEY,
+	id   TEXT NOT NULL,
+	rate DOUBLE PRECISION NOT NULL
+);
+CREATE INDEX IF NOT EXISTS drivers_id_idx ON drivers (id);
+`
+
+// PostgresDriverStore persists the roster in Postgres.
+type PostgresDriverStore struct {
+	db *sql.DB
+}
+
+// NewPostgresDriverStore connects to dsn and ensures the drivers table exists.
+func NewPostgresDriverStore(dsn string) (*PostgresDriverStore, error) {
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		return nil, err
+	}
+	if _, err1 := db.Exec(createDriversTable); err1 != nil {
+		return nil, err1
+	}
+	return &PostgresDriverStore{db: db}, nil
+}
+
+func (s *PostgresDriverStore) CreateDriver(driver Driver) error {
+	_, err := s.db.Exec("INSERT INTO drivers (name, id, rate) VALUES ($1, $2, $3)", driver.Name, driver.Id, driver.Rate)
+	return err
+}
+
+func (s *PostgresDriverStore) GetDriver(name string) (Driver, error) {
+	var driver Driver
+	row := s.db.QueryRow("SELECT name, id, rate FROM drivers WHERE name = $1", name)
+	if err := row.Scan(&driver.Name, &driver.Id, &driver.Rate); err == sql.ErrNoRows {
+		return Driver{}, ErrNotFound
+	} else if err != nil {
+		return Driver{}, err
+	}
+	return driver, nil
+}
+
+func (s *PostgresDriverStore) ListDrivers() (map[string]Driver, error) {
+	rows, err := s.db.Query("SELECT name, id, rate FROM drivers")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	drivers := make(map[string]Driver)
+	for rows.Next() {
+		var driver Driver
+		if err1 := rows.Scan(&driver.Name, &driver.Id, &driver.Rate); err1 != nil {
+			return nil, err1
+		}
+		drivers[driver.Name] = driver
+	}
+	return drivers, rows.Err()
+}
+
+func (s *PostgresDriverStore) UpdateDriver(driver Driver) error {
+	res, err := s.db.Exec("UPDATE drivers SET id = $1, rate = $2 WHERE name = $3", driver.Id, driver.Rate, driver.Name)
+	if err != nil {
+		return err
+	}
+	n, err1 := res.RowsAffected()
+	if err1 != nil {
+		return err1
+	}
+	if n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (s *PostgresDriverStore) DeleteDriver(name string) error {
+	res, err := s.db.Exec("DELETE FROM drivers WHERE name = $1", name)
+	if err != nil {
+		return err
+	}
+	n, err1 := res.RowsAffected()
+	if err1 != nil {
+		return err1
+	}
+	if n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// createUsersTable sets up the users table with a unique index on key,
+// since every auth request looks a user up by key.
+const createUsersTable = `
+CREATE TABLE IF NOT EXISTS users (
+	key TEXT PRIMARY KEY,
+	id  TEXT NOT NULL
+);
+`
+
+// PostgresUserStore resolves esr_auth API keys to user ids from Postgres.
+type PostgresUserStore struct {
+	db *sql.DB
+}
+
+// NewPostgresUserStore connects to dsn and ensures the users table exists.
+func NewPostgresUserStore(dsn string) (*PostgresUserStore, error) {
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		return nil, err
+	}
+	if _, err1 := db.Exec(createUsersTable); err1 != nil {
+		return nil, err1
+	}
+	return &PostgresUserStore{db: db}, nil
+}
+
+func (s *PostgresUserStore) LookupByKey(key string) (User, error) {
+	var user User
+	row := s.db.QueryRow("SELECT key, id FROM users WHERE key = $1", key)
+	if err := row.Scan(&user.Key, &user.Id); err == sql.ErrNoRows {
+		return User{}, ErrNotFound
+	} else if err != nil {
+		return User{}, err
+	}
+	return user, nil
+}
@@ -0,0 +1,73 @@
+// Package store provides the persistence layer for the esr microservices.
+// esr_drivers used to keep its roster in a plain map that was wiped on every
+// restart, and esr_auth re-read and linearly scanned a JSON file on every
+// request; this package lets either one be backed by something durable and
+// fast instead, chosen at startup via the STORE env var.
+package store
+
+import "errors"
+
+// ErrNotFound is returned when a lookup finds no matching record.
+var ErrNotFound = errors.New("store: not found")
+
+// ErrAlreadyExists is returned by CreateDriver when the name is taken.
+var ErrAlreadyExists = errors.New("store: already exists")
+
+// Driver is a roster entry, as used by esr_drivers.
+type Driver struct {
+	Id   string
+	Name string
+	Rate float64
+}
+
+// User is an authentication entry, as used by esr_auth.
+type User struct {
+	Id  string
+	Key string
+}
+
+// DriverStore persists the esr_drivers roster, keyed by driver name.
+type DriverStore interface {
+	CreateDriver(driver Driver) error
+	GetDriver(name string) (Driver, error)
+	ListDrivers() (map[string]Driver, error)
+	UpdateDriver(driver Driver) error
+	DeleteDriver(name string) error
+}
+
+// UserStore resolves esr_auth API keys to user ids.
+type UserStore interface {
+	LookupByKey(key string) (User, error)
+}
+
+// NewDriverStore builds a DriverStore of the given kind ("memory", "badger"
+// or "postgres"). dsn is interpreted per kind: ignored for memory, a
+// directory path for badger, a connection string for postgres.
+func NewDriverStore(kind, dsn string) (DriverStore, error) {
+	switch kind {
+	case "memory":
+		return NewMemoryDriverStore(), nil
+	case "badger":
+		return NewBadgerDriverStore(dsn)
+	case "postgres":
+		return NewPostgresDriverStore(dsn)
+	default:
+		return nil, errors.New("store: unknown STORE kind: " + kind)
+	}
+}
+
+// NewUserStore builds a UserStore of the given kind ("file", "badger" or
+// "postgres"). dsn is interpreted per kind: a JSON file path for file, a
+// directory path for badger, a connection string for postgres.
+func NewUserStore(kind, dsn string) (UserStore, error) {
+	switch kind {
+	case "file":
+		return NewFileUserStore(dsn)
+	case "badger":
+		return NewBadgerUserStore(dsn)
+	case "postgres":
+		return NewPostgresUserStore(dsn)
+	default:
+		return nil, errors.New("store: unknown STORE kind: " + kind)
+	}
+}